@@ -0,0 +1,252 @@
+// Package admission implements the gate PromSketch runs every dynamically
+// discovered label set through before a sketch is allocated for it. Without
+// it, remote-write ingestion from an unbounded label space (see
+// ps.EnsureSketch call sites) could allocate sketches without limit.
+package admission
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zzylol/prometheus-sketches/model/labels"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy decides whether a newly observed label set is allowed to get a
+// sketch, and tracks which existing series are eligible for eviction.
+type Policy interface {
+	// Admit reports whether lset may have a sketch created for it. If
+	// admitting lset would push the series count over MaxSeries, Admit
+	// evicts the least-recently-touched existing series to make room and
+	// returns them so the caller can tear down their sketches too.
+	Admit(lset labels.Labels) (ok bool, evicted []labels.Labels)
+	// Touch records that lset was just read or written, resetting its TTL
+	// clock and marking it most-recently-used for LRU eviction purposes.
+	Touch(lset labels.Labels)
+	// Evictable returns the label sets that have exceeded the policy's TTL,
+	// or that still push the active series count over its cap, oldest
+	// (least-recently-touched) first.
+	Evictable() []labels.Labels
+	// Forget removes lset from the policy's own bookkeeping; called once
+	// the caller has actually torn down its sketches.
+	Forget(lset labels.Labels)
+}
+
+// Config is the on-disk (YAML) representation of a Policy.
+type Config struct {
+	// MaxSeries caps the number of distinct label sets with live sketches.
+	// Zero means unlimited. Once the cap is reached, admitting a new series
+	// evicts the least-recently-touched existing one.
+	MaxSeries int `yaml:"max_series"`
+	// TTL is how long a series may go untouched before it becomes evictable.
+	// Zero means series are never evicted on idleness.
+	TTL time.Duration `yaml:"ttl"`
+	// AllowedMatchers restricts admission to label sets matching at least
+	// one of these selectors. An empty list allows everything.
+	AllowedMatchers []MatcherConfig `yaml:"allowed_matchers"`
+}
+
+// rawConfig mirrors Config for YAML decoding, with TTL as the duration
+// string (e.g. "1h") operators actually write, since yaml.v3 has no built-in
+// decoding from a duration string into a time.Duration-kind field - it only
+// knows how to assign a bare integer (nanoseconds) there.
+type rawConfig struct {
+	MaxSeries       int             `yaml:"max_series"`
+	TTL             string          `yaml:"ttl"`
+	AllowedMatchers []MatcherConfig `yaml:"allowed_matchers"`
+}
+
+// UnmarshalYAML parses TTL with time.ParseDuration instead of relying on
+// yaml.v3's default numeric decoding, so the "1h"-style duration strings
+// this package's own example config uses actually work.
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	var raw rawConfig
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	c.MaxSeries = raw.MaxSeries
+	c.AllowedMatchers = raw.AllowedMatchers
+	if raw.TTL == "" {
+		c.TTL = 0
+		return nil
+	}
+	d, err := time.ParseDuration(raw.TTL)
+	if err != nil {
+		return fmt.Errorf("admission: invalid ttl %q: %w", raw.TTL, err)
+	}
+	c.TTL = d
+	return nil
+}
+
+// MatcherConfig is one label-matcher entry in the YAML allowlist, e.g.
+//
+//   - name: fake_metric
+//     value: fake_machine_metric
+//     type: "="
+type MatcherConfig struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+	Type  string `yaml:"type"` // one of "=", "!=", "=~", "!~"
+}
+
+// lruPolicy is the default Policy: a max-series cap with real LRU eviction,
+// plus TTL-based idle eviction, with admission gated by an optional matcher
+// allowlist.
+type lruPolicy struct {
+	cfg Config
+
+	mu    sync.Mutex
+	order *list.List // list of string keys; front = most recently touched, back = least recently touched
+	elems map[string]*list.Element
+
+	matchers  []*labels.Matcher
+	lastSeen  map[string]time.Time
+	lastLabel map[string]labels.Labels
+}
+
+// New builds the default Policy from cfg. Invalid matcher entries in cfg are
+// skipped rather than failing construction, since a bad allowlist entry
+// shouldn't take down admission for every other series.
+func New(cfg Config) (Policy, error) {
+	p := &lruPolicy{
+		cfg:       cfg,
+		order:     list.New(),
+		elems:     make(map[string]*list.Element),
+		lastSeen:  make(map[string]time.Time),
+		lastLabel: make(map[string]labels.Labels),
+	}
+	for _, mc := range cfg.AllowedMatchers {
+		m, err := toMatcher(mc)
+		if err != nil {
+			continue
+		}
+		p.matchers = append(p.matchers, m)
+	}
+	return p, nil
+}
+
+func toMatcher(mc MatcherConfig) (*labels.Matcher, error) {
+	var mtype labels.MatchType
+	switch mc.Type {
+	case "", "=":
+		mtype = labels.MatchEqual
+	case "!=":
+		mtype = labels.MatchNotEqual
+	case "=~":
+		mtype = labels.MatchRegexp
+	case "!~":
+		mtype = labels.MatchNotRegexp
+	default:
+		return nil, fmt.Errorf("admission: unrecognized matcher type %q", mc.Type)
+	}
+	return labels.NewMatcher(mtype, mc.Name, mc.Value)
+}
+
+func (p *lruPolicy) Admit(lset labels.Labels) (bool, []labels.Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.matchers) > 0 && !p.matchesAllowlist(lset) {
+		return false, nil
+	}
+
+	key := lset.String()
+	if _, known := p.lastSeen[key]; known {
+		return true, nil
+	}
+
+	var evicted []labels.Labels
+	if p.cfg.MaxSeries > 0 {
+		for len(p.lastSeen) >= p.cfg.MaxSeries {
+			back := p.order.Back()
+			if back == nil {
+				break
+			}
+			evictKey := back.Value.(string)
+			evicted = append(evicted, p.lastLabel[evictKey])
+			p.removeLocked(evictKey)
+		}
+	}
+	return true, evicted
+}
+
+func (p *lruPolicy) matchesAllowlist(lset labels.Labels) bool {
+	for _, m := range p.matchers {
+		if m.Matches(lset.Get(m.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *lruPolicy) Touch(lset labels.Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := lset.String()
+	if el, ok := p.elems[key]; ok {
+		p.order.MoveToFront(el)
+	} else {
+		p.elems[key] = p.order.PushFront(key)
+	}
+	p.lastSeen[key] = time.Now()
+	p.lastLabel[key] = lset
+}
+
+// Evictable walks the LRU order from least- to most-recently-touched,
+// collecting series that are either TTL-expired or still pushing the
+// series count over MaxSeries, so both reasons to evict are surfaced
+// through the same oldest-first list.
+func (p *lruPolicy) Evictable() []labels.Labels {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var evictable []labels.Labels
+	seen := make(map[string]bool)
+
+	if p.cfg.TTL > 0 {
+		cutoff := time.Now().Add(-p.cfg.TTL)
+		for el := p.order.Back(); el != nil; el = el.Prev() {
+			key := el.Value.(string)
+			if !p.lastSeen[key].Before(cutoff) {
+				break // order is oldest-to-newest walking back-to-front; nothing further is expired
+			}
+			evictable = append(evictable, p.lastLabel[key])
+			seen[key] = true
+		}
+	}
+
+	if p.cfg.MaxSeries > 0 {
+		overflow := len(p.lastSeen) - p.cfg.MaxSeries
+		for el := p.order.Back(); el != nil && overflow > 0; el = el.Prev() {
+			key := el.Value.(string)
+			if seen[key] {
+				overflow--
+				continue
+			}
+			evictable = append(evictable, p.lastLabel[key])
+			seen[key] = true
+			overflow--
+		}
+	}
+
+	return evictable
+}
+
+func (p *lruPolicy) Forget(lset labels.Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(lset.String())
+}
+
+// removeLocked drops key from every index. Callers must hold p.mu.
+func (p *lruPolicy) removeLocked(key string) {
+	if el, ok := p.elems[key]; ok {
+		p.order.Remove(el)
+		delete(p.elems, key)
+	}
+	delete(p.lastSeen, key)
+	delete(p.lastLabel, key)
+}
@@ -0,0 +1,128 @@
+package admission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zzylol/prometheus-sketches/model/labels"
+)
+
+func mustLabels(t *testing.T, pairs ...string) labels.Labels {
+	t.Helper()
+	b := labels.NewBuilder(labels.Labels{})
+	for i := 0; i < len(pairs); i += 2 {
+		b.Set(pairs[i], pairs[i+1])
+	}
+	return b.Labels()
+}
+
+func TestLRUPolicyAdmitEvictsOldestOverCap(t *testing.T) {
+	p, err := New(Config{MaxSeries: 2})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	a := mustLabels(t, "machineid", "a")
+	b := mustLabels(t, "machineid", "b")
+	c := mustLabels(t, "machineid", "c")
+
+	for _, lset := range []labels.Labels{a, b} {
+		ok, evicted := p.Admit(lset)
+		if !ok || len(evicted) != 0 {
+			t.Fatalf("Admit(%v) = %v, %v; want true, none", lset, ok, evicted)
+		}
+		p.Touch(lset)
+	}
+
+	// Admitting a third series over the cap of 2 should evict "a", since it
+	// was touched first and never touched again.
+	ok, evicted := p.Admit(c)
+	if !ok {
+		t.Fatalf("Admit(c) = false, want true")
+	}
+	if len(evicted) != 1 || evicted[0].String() != a.String() {
+		t.Fatalf("Admit(c) evicted = %v, want [%v]", evicted, a)
+	}
+}
+
+func TestLRUPolicyAdmitRespectsRecentTouch(t *testing.T) {
+	p, err := New(Config{MaxSeries: 2})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	a := mustLabels(t, "machineid", "a")
+	b := mustLabels(t, "machineid", "b")
+	c := mustLabels(t, "machineid", "c")
+
+	p.Admit(a)
+	p.Touch(a)
+	p.Admit(b)
+	p.Touch(b)
+
+	// Touching "a" again makes "b" the least-recently-used instead.
+	p.Touch(a)
+
+	_, evicted := p.Admit(c)
+	if len(evicted) != 1 || evicted[0].String() != b.String() {
+		t.Fatalf("Admit(c) evicted = %v, want [%v]", evicted, b)
+	}
+}
+
+func TestLRUPolicyAdmitRejectsOutsideAllowlist(t *testing.T) {
+	p, err := New(Config{
+		AllowedMatchers: []MatcherConfig{{Name: "job", Value: "promsketch"}},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	allowed := mustLabels(t, "job", "promsketch")
+	rejected := mustLabels(t, "job", "other")
+
+	if ok, _ := p.Admit(allowed); !ok {
+		t.Errorf("Admit(allowed) = false, want true")
+	}
+	if ok, _ := p.Admit(rejected); ok {
+		t.Errorf("Admit(rejected) = true, want false")
+	}
+}
+
+func TestLRUPolicyEvictableTTLExpiry(t *testing.T) {
+	p, err := New(Config{TTL: time.Millisecond})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	a := mustLabels(t, "machineid", "a")
+	p.Admit(a)
+	p.Touch(a)
+
+	time.Sleep(5 * time.Millisecond)
+
+	evictable := p.Evictable()
+	if len(evictable) != 1 || evictable[0].String() != a.String() {
+		t.Fatalf("Evictable() = %v, want [%v]", evictable, a)
+	}
+
+	p.Forget(a)
+	if evictable := p.Evictable(); len(evictable) != 0 {
+		t.Fatalf("Evictable() after Forget = %v, want none", evictable)
+	}
+}
+
+func TestToMatcherRejectsUnrecognizedType(t *testing.T) {
+	if _, err := toMatcher(MatcherConfig{Name: "job", Value: "x", Type: "??"}); err == nil {
+		t.Fatal("toMatcher with unrecognized type = nil error, want error")
+	}
+}
+
+func TestToMatcherDefaultsEmptyTypeToEqual(t *testing.T) {
+	m, err := toMatcher(MatcherConfig{Name: "job", Value: "x"})
+	if err != nil {
+		t.Fatalf("toMatcher: %v", err)
+	}
+	if m.Type != labels.MatchEqual {
+		t.Errorf("toMatcher default type = %v, want MatchEqual", m.Type)
+	}
+}
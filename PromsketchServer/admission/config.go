@@ -0,0 +1,26 @@
+package admission
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig reads a Policy Config from a YAML file on disk, e.g.:
+//
+//	max_series: 50000
+//	ttl: 1h
+//	allowed_matchers:
+//	  - name: job
+//	    value: promsketch
+func LoadConfig(path string) (Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
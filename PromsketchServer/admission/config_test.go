@@ -0,0 +1,66 @@
+package admission
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigParsesDurationTTL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "admission.yaml")
+	const doc = `
+max_series: 50000
+ttl: 1h
+allowed_matchers:
+  - name: job
+    value: promsketch
+`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if cfg.MaxSeries != 50000 {
+		t.Errorf("MaxSeries = %d, want 50000", cfg.MaxSeries)
+	}
+	if cfg.TTL != time.Hour {
+		t.Errorf("TTL = %v, want 1h", cfg.TTL)
+	}
+	if len(cfg.AllowedMatchers) != 1 || cfg.AllowedMatchers[0].Name != "job" {
+		t.Errorf("AllowedMatchers = %v, want one matcher on job", cfg.AllowedMatchers)
+	}
+}
+
+func TestLoadConfigRejectsInvalidTTL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "admission.yaml")
+	if err := os.WriteFile(path, []byte("ttl: not-a-duration\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("LoadConfig with invalid ttl = nil error, want error")
+	}
+}
+
+func TestLoadConfigDefaultsTTLToZero(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "admission.yaml")
+	if err := os.WriteFile(path, []byte("max_series: 10\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.TTL != 0 {
+		t.Errorf("TTL = %v, want 0", cfg.TTL)
+	}
+}
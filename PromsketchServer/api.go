@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Froot-NetSys/PromSketch-Standalone/PromsketchServer/promql"
+	"github.com/gin-gonic/gin"
+	"github.com/zzylol/prometheus-sketches/model/labels"
+	"github.com/zzylol/prometheus-sketches/promql/parser"
+)
+
+// registerPromQLRoutes wires up the Prometheus HTTP API surface
+// (api/v1/query, query_range, series, labels, label/<name>/values) so
+// Grafana and other stock Prometheus clients can query PromSketch directly,
+// instead of going through the bespoke /query endpoint.
+func registerPromQLRoutes(router *gin.Engine) {
+	v1 := router.Group("/api/v1")
+	v1.GET("/query", handleAPIQuery)
+	v1.GET("/query_range", handleAPIQueryRange)
+	v1.GET("/series", handleAPISeries)
+	v1.GET("/labels", handleAPILabels)
+	v1.GET("/label/:name/values", handleAPILabelValues)
+}
+
+// handleAPIQuery implements GET /api/v1/query: a single PromQL expression
+// evaluated at instant "time".
+func handleAPIQuery(c *gin.Context) {
+	queryStr := c.Query("query")
+	evalTime, err := parseAPITime(c.DefaultQuery("time", ""), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, promql.NewErrorResponse("bad_data", err.Error()))
+		return
+	}
+
+	q, err := promql.Parse(queryStr)
+	if err != nil {
+		resp, status := evalUnsupported(queryStr, err)
+		c.JSON(status, resp)
+		return
+	}
+
+	mint := evalTime.Add(-q.Range).UnixMilli()
+	maxt := evalTime.UnixMilli()
+	lset := q.ToLabels()
+
+	if !ps.LookUp(lset, q.Func, mint, maxt) {
+		c.JSON(http.StatusOK, &promql.APIResponse{Status: "success", Data: &promql.QueryData{ResultType: "vector", Result: []promql.Sample{}}})
+		return
+	}
+
+	vector, _ := ps.Eval(q.Func, lset, q.Arg, mint, maxt, maxt)
+	if len(vector) == 0 {
+		c.JSON(http.StatusOK, &promql.APIResponse{Status: "success", Data: &promql.QueryData{ResultType: "vector", Result: []promql.Sample{}}})
+		return
+	}
+	last := vector[len(vector)-1]
+	c.JSON(http.StatusOK, promql.NewVectorResponse(labelsToMap(lset), promql.Point{T: last.T, F: last.F}))
+}
+
+// handleAPIQueryRange implements GET /api/v1/query_range: a PromQL
+// expression evaluated over [start,end] at a fixed step.
+func handleAPIQueryRange(c *gin.Context) {
+	queryStr := c.Query("query")
+	start, err := parseAPITime(c.Query("start"), time.Time{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, promql.NewErrorResponse("bad_data", "invalid 'start': "+err.Error()))
+		return
+	}
+	end, err := parseAPITime(c.Query("end"), time.Time{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, promql.NewErrorResponse("bad_data", "invalid 'end': "+err.Error()))
+		return
+	}
+
+	step, err := parseAPIStep(c.DefaultQuery("step", ""))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, promql.NewErrorResponse("bad_data", "invalid 'step': "+err.Error()))
+		return
+	}
+
+	q, err := promql.Parse(queryStr)
+	if err != nil {
+		resp, status := evalUnsupported(queryStr, err)
+		c.JSON(status, resp)
+		return
+	}
+
+	mint := start.Add(-q.Range).UnixMilli()
+	maxt := end.UnixMilli()
+	lset := q.ToLabels()
+
+	if !ps.LookUp(lset, q.Func, mint, maxt) {
+		c.JSON(http.StatusOK, &promql.APIResponse{Status: "success", Data: &promql.QueryData{ResultType: "matrix", Result: []promql.Sample{}}})
+		return
+	}
+
+	vector, _ := ps.Eval(q.Func, lset, q.Arg, mint, maxt, end.UnixMilli())
+	points := make([]promql.Point, 0, len(vector))
+	for _, s := range vector {
+		points = append(points, promql.Point{T: s.T, F: s.F})
+	}
+	points = resampleToStep(points, start.UnixMilli(), end.UnixMilli(), step)
+	c.JSON(http.StatusOK, promql.NewMatrixResponse(labelsToMap(lset), points))
+}
+
+// defaultQueryRangeStep is used when the caller omits `step`, matching
+// Prometheus's own 1m default resolution.
+const defaultQueryRangeStep = time.Minute
+
+// parseAPIStep parses the Prometheus `step` query parameter: either a Go
+// duration string as accepted by time.ParseDuration (e.g. "30s"; units such
+// as "1d" or "1w" are not accepted) or a plain number of seconds.
+func parseAPIStep(v string) (time.Duration, error) {
+	if v == "" {
+		return defaultQueryRangeStep, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d, nil
+	}
+	var seconds float64
+	if _, err := fmt.Sscanf(v, "%f", &seconds); err != nil {
+		return 0, fmt.Errorf("cannot parse step %q", v)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// resampleToStep buckets ps.Eval's raw result points onto the step grid
+// Prometheus clients expect from query_range: one point per
+// [startMs, endMs] step, taking the latest sample at or before each grid
+// timestamp. ps.Eval returns points at whatever resolution the sketch was
+// inserted at, which generally doesn't line up with the requested step.
+func resampleToStep(points []promql.Point, startMs, endMs int64, step time.Duration) []promql.Point {
+	if len(points) == 0 || step <= 0 {
+		return points
+	}
+
+	stepMs := step.Milliseconds()
+	if stepMs <= 0 {
+		return points
+	}
+
+	resampled := make([]promql.Point, 0, (endMs-startMs)/stepMs+1)
+	idx := 0
+	var last *promql.Point
+	for t := startMs; t <= endMs; t += stepMs {
+		for idx < len(points) && points[idx].T <= t {
+			last = &points[idx]
+			idx++
+		}
+		if last != nil {
+			resampled = append(resampled, promql.Point{T: t, F: last.F})
+		}
+	}
+	return resampled
+}
+
+// handleAPISeries implements GET /api/v1/series: it returns the label sets
+// currently known to the sketch registry that match any of the given
+// `match[]` selectors (Prometheus ORs across repeated match[] params; since
+// this is an OR-of-selectors and knownLabelSets has no index to push the
+// matchers into, it's evaluated by scanning the full registry per selector).
+// PromSketch has no independent series index yet, so this walks the same
+// registry ensureSketches and POST /admin/sketches populate.
+func handleAPISeries(c *gin.Context) {
+	selectors := c.QueryArray("match[]")
+	if len(selectors) == 0 {
+		c.JSON(http.StatusOK, gin.H{"status": "success", "data": knownSeries()})
+		return
+	}
+
+	var matcherSets [][]*labels.Matcher
+	for _, sel := range selectors {
+		matchers, err := parseMatchSelector(sel)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'match[]' selector: " + err.Error()})
+			return
+		}
+		matcherSets = append(matcherSets, promql.TranslateMetricName(matchers))
+	}
+
+	var matched []map[string]string
+	for _, lset := range knownLabelSets() {
+		for _, matchers := range matcherSets {
+			if matchesAll(lset, matchers) {
+				matched = append(matched, labelsToMap(lset))
+				break
+			}
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": matched})
+}
+
+// handleAPILabels implements GET /api/v1/labels.
+func handleAPILabels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": knownLabelNames()})
+}
+
+// handleAPILabelValues implements GET /api/v1/label/<name>/values.
+func handleAPILabelValues(c *gin.Context) {
+	name := c.Param("name")
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": knownLabelValues(name)})
+}
+
+// evalUnsupported decides how to respond when promql.Parse reports the
+// query isn't sketch-eligible: fall back to the scan-based evaluator rather
+// than failing the request outright.
+func evalUnsupported(queryStr string, parseErr error) (*promql.APIResponse, int) {
+	if _, ok := parseErr.(*promql.ErrUnsupported); !ok {
+		return promql.NewErrorResponse("bad_data", parseErr.Error()), http.StatusBadRequest
+	}
+	result, err := scanEval(queryStr)
+	if err != nil {
+		log.Printf("[scanEval] fallback failed for %q: %v", queryStr, err)
+		return promql.NewErrorResponse("execution", err.Error()), http.StatusUnprocessableEntity
+	}
+	return result, http.StatusOK
+}
+
+// scanDefaultWindow is how far back scanEval looks when approximating a bare
+// metric selector's current value, since PromSketch retains no instant raw
+// sample to look up directly.
+const scanDefaultWindow = 5 * time.Minute
+
+// scanEval is the fallback path for PromQL expressions that have no
+// sketch-backed *_over_time function call. PromSketch retains no raw
+// samples, so most of PromQL (arbitrary binary/aggregation expressions,
+// instant-vector functions other than a bare selector) genuinely can't be
+// evaluated here and still returns a clear "unimplemented" error. The one
+// case worth supporting is a bare metric selector with no function at all
+// (e.g. `up{job="promsketch"}`) - Grafana's "Explore" and ad-hoc dashboards
+// issue these constantly - which is approximated as avg_over_time across
+// scanDefaultWindow for every matching series in the registry, scanning the
+// registry by matcher rather than requiring an exact label set.
+func scanEval(queryStr string) (*promql.APIResponse, error) {
+	matchers, err := parser.ParseMetricSelector(queryStr)
+	if err != nil {
+		return nil, fmt.Errorf("scan-based evaluation of %q is not implemented: PromSketch only retains sketch-summarized data accessible via *_over_time functions", queryStr)
+	}
+	matchers = promql.TranslateMetricName(matchers)
+
+	now := time.Now()
+	mint := now.Add(-scanDefaultWindow).UnixMilli()
+	maxt := now.UnixMilli()
+
+	var samples []promql.Sample
+	for _, lset := range knownLabelSets() {
+		if !matchesAll(lset, matchers) {
+			continue
+		}
+		if !ps.LookUp(lset, "avg_over_time", mint, maxt) {
+			continue
+		}
+		vector, _ := ps.Eval("avg_over_time", lset, 0, mint, maxt, maxt)
+		if len(vector) == 0 {
+			continue
+		}
+		last := vector[len(vector)-1]
+		samples = append(samples, promql.Sample{
+			Metric: labelsToMap(lset),
+			Value:  &[2]interface{}{float64(last.T) / 1000, strconv.FormatFloat(last.F, 'f', -1, 64)},
+		})
+	}
+
+	return &promql.APIResponse{Status: "success", Data: &promql.QueryData{ResultType: "vector", Result: samples}}, nil
+}
+
+func parseAPITime(v string, def time.Time) (time.Time, error) {
+	if v == "" {
+		if def.IsZero() {
+			return def, fmt.Errorf("missing required time parameter")
+		}
+		return def, nil
+	}
+	if ts, err := time.Parse(time.RFC3339, v); err == nil {
+		return ts, nil
+	}
+	var seconds float64
+	if _, err := fmt.Sscanf(v, "%f", &seconds); err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse time %q", v)
+	}
+	return time.UnixMilli(int64(seconds * 1000)), nil
+}
+
+func labelsToMap(lset labels.Labels) map[string]string {
+	m := make(map[string]string, len(lset))
+	for _, l := range lset {
+		m[l.Name] = l.Value
+	}
+	return m
+}
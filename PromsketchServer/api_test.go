@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Froot-NetSys/PromSketch-Standalone/PromsketchServer/promql"
+)
+
+func TestParseAPIStep(t *testing.T) {
+	cases := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", defaultQueryRangeStep},
+		{"30s", 30 * time.Second},
+		{"15", 15 * time.Second},
+	}
+	for _, tc := range cases {
+		got, err := parseAPIStep(tc.in)
+		if err != nil {
+			t.Errorf("parseAPIStep(%q) error: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseAPIStep(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+
+	if _, err := parseAPIStep("not-a-duration"); err == nil {
+		t.Error("parseAPIStep(\"not-a-duration\") = nil error, want error")
+	}
+}
+
+func TestResampleToStep(t *testing.T) {
+	points := []promql.Point{
+		{T: 0, F: 1},
+		{T: 1000, F: 2},
+		{T: 2500, F: 3},
+	}
+
+	got := resampleToStep(points, 0, 3000, time.Second)
+
+	want := []promql.Point{
+		{T: 0, F: 1},
+		{T: 1000, F: 2},
+		{T: 2000, F: 2},
+		{T: 3000, F: 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("resampleToStep = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("resampleToStep[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResampleToStepEmpty(t *testing.T) {
+	if got := resampleToStep(nil, 0, 1000, time.Second); len(got) != 0 {
+		t.Errorf("resampleToStep(nil) = %v, want empty", got)
+	}
+}
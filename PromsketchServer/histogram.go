@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/zzylol/prometheus-sketches/model/histogram"
+)
+
+// HistogramPayload is the JSON shape of a Prometheus native (sparse bucket)
+// histogram sample, mirroring the fields of histogram.Histogram closely
+// enough that toHistogram is a straight field copy. Python ingesters that
+// can produce native histograms (rather than classic bucketed counters) can
+// send this instead of MetricPayload.Value; when both are set, handleIngest
+// inserts only the histogram.
+type HistogramPayload struct {
+	Schema         int32           `json:"schema"`
+	ZeroThreshold  float64         `json:"zero_threshold"`
+	ZeroCount      uint64          `json:"zero_count"`
+	Count          uint64          `json:"count"`
+	Sum            float64         `json:"sum"`
+	PositiveSpans  []HistogramSpan `json:"positive_spans"`
+	PositiveDeltas []int64         `json:"positive_deltas"`
+	NegativeSpans  []HistogramSpan `json:"negative_spans"`
+	NegativeDeltas []int64         `json:"negative_deltas"`
+}
+
+// HistogramSpan is one run of populated buckets: Offset buckets are skipped
+// after the previous span, then Length consecutive buckets are populated.
+type HistogramSpan struct {
+	Offset int32  `json:"offset"`
+	Length uint32 `json:"length"`
+}
+
+// toHistogram converts the wire payload into the sparse-histogram type
+// ps.SketchInsertHistogram expects.
+func (p *HistogramPayload) toHistogram() *histogram.Histogram {
+	h := &histogram.Histogram{
+		Schema:          p.Schema,
+		ZeroThreshold:   p.ZeroThreshold,
+		ZeroCount:       p.ZeroCount,
+		Count:           p.Count,
+		Sum:             p.Sum,
+		PositiveSpans:   make([]histogram.Span, len(p.PositiveSpans)),
+		PositiveBuckets: p.PositiveDeltas,
+		NegativeSpans:   make([]histogram.Span, len(p.NegativeSpans)),
+		NegativeBuckets: p.NegativeDeltas,
+	}
+	for i, s := range p.PositiveSpans {
+		h.PositiveSpans[i] = histogram.Span{Offset: s.Offset, Length: s.Length}
+	}
+	for i, s := range p.NegativeSpans {
+		h.NegativeSpans[i] = histogram.Span{Offset: s.Offset, Length: s.Length}
+	}
+	return h
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newBaseLogger builds the process-wide slog.Logger. PROMSKETCH_LOG_FORMAT
+// selects the handler: JSON by default (production-friendly, machine
+// parseable), or "text" for local development.
+func newBaseLogger() *slog.Logger {
+	var handler slog.Handler
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if os.Getenv("PROMSKETCH_LOG_FORMAT") == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+	return slog.New(handler)
+}
+
+var baseLogger = newBaseLogger()
+
+type loggerCtxKey struct{}
+
+// loggerFromContext returns the request-scoped logger requestLogger
+// middleware stored on the request context, or baseLogger if none is set
+// (e.g. code paths not reached through a Gin handler).
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerCtxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return baseLogger
+}
+
+// requestLogger is Gin middleware that assigns each request a short
+// correlation ID, attaches it to a request-scoped slog.Logger stored on
+// c.Request's context, and emits one structured access log line per request
+// once the handler returns. Handlers enrich that line by calling
+// c.Set(...) with any of the well-known fields below before returning.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		reqID := newRequestID()
+		logger := baseLogger.With("request_id", reqID)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), loggerCtxKey{}, logger))
+
+		c.Next()
+
+		fields := []any{
+			"method", c.Request.Method,
+			"path", c.FullPath(),
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+		}
+		for _, key := range []string{"func", "metric", "mint", "maxt", "covered", "result_count"} {
+			if v, ok := c.Get(key); ok {
+				fields = append(fields, key, v)
+			}
+		}
+		logger.Info("request", fields...)
+	}
+}
+
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// dedup rate-limits noisy, high-frequency log lines (e.g. a per-sample or
+// per-batch message that would otherwise drown production logs) so the same
+// key logs at most once per window, regardless of call volume.
+type dedup struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newDedup(window time.Duration) *dedup {
+	return &dedup{window: window, last: make(map[string]time.Time)}
+}
+
+// Allow reports whether a log line keyed by key should actually be emitted
+// right now, and records that it fired if so.
+func (d *dedup) Allow(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	if last, ok := d.last[key]; ok && now.Sub(last) < d.window {
+		return false
+	}
+	d.last[key] = now
+	return true
+}
+
+// sketchCoverageLog rate-limits handleQuery's "sketch data not covered" line
+// and handleIngest's "batch ingested" line, which would otherwise fire on
+// every uncovered /query call and every /ingest call and dominate production
+// log volume.
+var sketchCoverageLog = newDedup(10 * time.Second)
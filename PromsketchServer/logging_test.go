@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupAllowRateLimits(t *testing.T) {
+	d := newDedup(50 * time.Millisecond)
+
+	if !d.Allow("a") {
+		t.Fatal("Allow(a) first call = false, want true")
+	}
+	if d.Allow("a") {
+		t.Fatal("Allow(a) immediate second call = true, want false")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !d.Allow("a") {
+		t.Fatal("Allow(a) after window elapsed = false, want true")
+	}
+}
+
+func TestDedupAllowIsPerKey(t *testing.T) {
+	d := newDedup(time.Minute)
+
+	if !d.Allow("a") {
+		t.Fatal("Allow(a) = false, want true")
+	}
+	if !d.Allow("b") {
+		t.Fatal("Allow(b) = false, want true (independent key from a)")
+	}
+}
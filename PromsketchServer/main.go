@@ -6,11 +6,13 @@ import (
 	"math"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/SieDeta/promsketch_std/promsketch"    // Replace with your actual module path
+	"github.com/SieDeta/promsketch_std/promsketch"       // Replace with your actual module path
 	"github.com/gin-gonic/gin"                           // Popular Go web framework
 	"github.com/zzylol/prometheus-sketches/model/labels" // This path may need to match your project structure
 )
@@ -23,17 +25,94 @@ type IngestPayload struct {
 
 // Structure for each metric in the payload
 type MetricPayload struct {
-	Name   string            `json:"name"`   // Metric name (e.g., "fake_machine_metric")
-	Labels map[string]string `json:"labels"` // Metric labels (e.g., {"machineid": "machine_0"})
-	Value  float64           `json:"value"`  // Metric value
+	Name      string            `json:"name"`                // Metric name (e.g., "fake_machine_metric")
+	Labels    map[string]string `json:"labels"`              // Metric labels (e.g., {"machineid": "machine_0"})
+	Value     float64           `json:"value"`               // Metric value
+	Histogram *HistogramPayload `json:"histogram,omitempty"` // Native histogram sample, mutually exclusive with Value
 }
 
 // Global PromSketches instance
 var ps *promsketch.PromSketches
 
+// seriesRegistry tracks the distinct label sets PromSketch has seen, purely
+// so /api/v1/series, /api/v1/labels and /api/v1/label/<name>/values have
+// something to answer from. PromSketch itself has no series index - it only
+// knows about sketches keyed by label set - so this is a thin, best-effort
+// mirror built from the label sets ensureSketches (handleIngest and the
+// remote-write handlers) and POST /admin/sketches admit.
+var seriesRegistry = struct {
+	sync.Mutex
+	sets map[string]labels.Labels
+}{sets: make(map[string]labels.Labels)}
+
+func rememberSeries(lset labels.Labels) {
+	seriesRegistry.Lock()
+	defer seriesRegistry.Unlock()
+	seriesRegistry.sets[lset.String()] = lset
+}
+
+func forgetSeries(lset labels.Labels) {
+	seriesRegistry.Lock()
+	defer seriesRegistry.Unlock()
+	delete(seriesRegistry.sets, lset.String())
+}
+
+func knownLabelSets() []labels.Labels {
+	seriesRegistry.Lock()
+	defer seriesRegistry.Unlock()
+	out := make([]labels.Labels, 0, len(seriesRegistry.sets))
+	for _, lset := range seriesRegistry.sets {
+		out = append(out, lset)
+	}
+	return out
+}
+
+func knownSeries() []map[string]string {
+	seriesRegistry.Lock()
+	defer seriesRegistry.Unlock()
+	out := make([]map[string]string, 0, len(seriesRegistry.sets))
+	for _, lset := range seriesRegistry.sets {
+		out = append(out, labelsToMap(lset))
+	}
+	return out
+}
+
+func knownLabelNames() []string {
+	seriesRegistry.Lock()
+	defer seriesRegistry.Unlock()
+	seen := map[string]bool{}
+	names := []string{}
+	for _, lset := range seriesRegistry.sets {
+		for _, l := range lset {
+			if !seen[l.Name] {
+				seen[l.Name] = true
+				names = append(names, l.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func knownLabelValues(name string) []string {
+	seriesRegistry.Lock()
+	defer seriesRegistry.Unlock()
+	seen := map[string]bool{}
+	values := []string{}
+	for _, lset := range seriesRegistry.sets {
+		v := lset.Get(name)
+		if v != "" && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
 func init() {
 	ps = promsketch.NewPromSketches()
-	log.Println("PromSketches instance initialized.")
+	baseLogger.Info("PromSketches instance initialized")
 
 	// Get the number of time series from environment variable or default (for testing)
 	// For production use, this may come from configuration.
@@ -58,20 +137,21 @@ func init() {
 
 		// Initialize sketches for all expected query functions
 		if err := ps.NewSketchCacheInstance(lset, "avg_over_time", defaultTimeWindow, defaultItemWindow, defaultValueScale); err != nil {
-			log.Printf("Error creating sketch for avg_over_time on %v: %v", lset, err)
+			baseLogger.Error("failed to create sketch", "func", "avg_over_time", "lset", lset.String(), "error", err)
 		}
 		if err := ps.NewSketchCacheInstance(lset, "quantile_over_time", defaultTimeWindow, defaultItemWindow, defaultValueScale); err != nil {
-			log.Printf("Error creating sketch for quantile_over_time on %v: %v", lset, err)
+			baseLogger.Error("failed to create sketch", "func", "quantile_over_time", "lset", lset.String(), "error", err)
 		}
 		if err := ps.NewSketchCacheInstance(lset, "entropy_over_time", defaultTimeWindow, defaultItemWindow, defaultValueScale); err != nil {
-			log.Printf("Error creating sketch for entropy_over_time on %v: %v", lset, err)
+			baseLogger.Error("failed to create sketch", "func", "entropy_over_time", "lset", lset.String(), "error", err)
 		}
 	}
-	log.Printf("Initial sketches created for %d time series.", numTimeseriesInit)
+	baseLogger.Info("initial sketches created", "count", numTimeseriesInit)
 }
 
 func main() {
 	router := gin.Default()
+	router.Use(requestLogger())
 
 	// Endpoint to receive metric data from the Python Ingester
 	// Data is sent as a JSON-formatted POST request
@@ -81,6 +161,19 @@ func main() {
 	// Queries use a GET request with URL parameters
 	router.GET("/query", handleQuery)
 
+	// Prometheus-compatible HTTP API (/api/v1/query, query_range, series,
+	// labels, label/<name>/values) so Grafana and other stock Prometheus
+	// clients can target PromSketch directly.
+	registerPromQLRoutes(router)
+
+	// Prometheus remote_write sink (v1 and v2 wire formats), so PromSketch
+	// can be scraped directly instead of through the bespoke /ingest JSON API.
+	registerRemoteWriteRoute(router)
+
+	// Operator endpoints for explicit sketch lifecycle management, plus the
+	// Prometheus /metrics collector.
+	registerAdminRoutes(router)
+
 	// Simple endpoint to check server status
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "UP", "message": "PromSketch Go server is running."})
@@ -96,9 +189,11 @@ var totalIngested int64
 
 // handleIngest receives metric data from custom_data_ingester.py
 func handleIngest(c *gin.Context) {
+	logger := loggerFromContext(c.Request.Context())
+
 	var payload IngestPayload
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		log.Printf("Error binding JSON payload: %v", err)
+		logger.Error("invalid ingest payload", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid JSON payload: %v", err.Error())})
 		return
 	}
@@ -115,14 +210,27 @@ func handleIngest(c *gin.Context) {
 		lsetBuilder.Set("fake_metric", metric.Name)
 		lset := lsetBuilder.Labels()
 
-		// Call SketchInsert from your PromSketches implementation
-		// This updates all relevant sketches for the given lset, timestamp, and value
-		// Assumes NewSketchCacheInstance has already been called during startup
-		// or is called on-demand if you support dynamic creation
+		// Lazily allocate sketches for label sets we haven't seen before,
+		// instead of relying solely on init()'s fixed machine_0..N fixture.
+		// The admission policy may reject lset (allowlist), in which case the
+		// sample is dropped rather than silently kept; reaching the series
+		// cap evicts the least-recently-touched series instead of rejecting.
+		if !ensureSketches(lset) {
+			logger.Warn("dropping sample: rejected by admission policy", "lset", lset.String())
+			continue
+		}
 
-		err := ps.SketchInsert(lset, payload.Timestamp, metric.Value)
+		var err error
+		if metric.Histogram != nil {
+			if ferr := ps.EnsureSketch(lset, "histogram_quantile_over_time", defaultSketchConfig); ferr != nil {
+				logger.Error("EnsureSketch failed", "func", "histogram_quantile_over_time", "lset", lset.String(), "error", ferr)
+			}
+			err = ps.SketchInsertHistogram(lset, payload.Timestamp, metric.Histogram.toHistogram())
+		} else {
+			err = ps.SketchInsert(lset, payload.Timestamp, metric.Value)
+		}
 		if err != nil {
-			log.Printf("Failed to insert data for %v (timestamp %d, value %.2f): %v", lset, payload.Timestamp, metric.Value, err)
+			logger.Error("insert failed", "lset", lset.String(), "timestamp", payload.Timestamp, "value", metric.Value, "error", err)
 			// Don't return error here to allow batch processing to continue
 			continue
 		}
@@ -130,47 +238,51 @@ func handleIngest(c *gin.Context) {
 	}
 
 	totalIngested += int64(ingestedCount)
-	log.Printf("Batch ingested: %d, Total ingested: %d", ingestedCount, totalIngested)
+	if sketchCoverageLog.Allow("ingest_batch") {
+		logger.Info("batch ingested", "ingested_count", ingestedCount, "total_ingested", totalIngested)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "success", "ingested_metrics_count": ingestedCount})
-	log.Printf("Successfully ingested %d metrics.", ingestedCount)
 }
 
 // handleQuery processes query requests from EvalData.py
 func handleQuery(c *gin.Context) {
+	logger := loggerFromContext(c.Request.Context())
+
 	funcName := c.Query("func")
 	metricName := c.Query("metric")
+	c.Set("func", funcName)
+	c.Set("metric", metricName)
 
 	mintStr := c.Query("mint")
 	maxtStr := c.Query("maxt")
 
-	log.Printf("DEBUG Query: func=%s, metric=%s, mintStr='%s', maxtStr='%s'", funcName, metricName, mintStr, maxtStr)
-
 	mint, err := strconv.ParseInt(mintStr, 10, 64)
 	if err != nil {
-		log.Printf("ERROR: Failed to parse 'mint' parameter '%s': %v", mintStr, err)
+		logger.Error("failed to parse 'mint' parameter", "mint", mintStr, "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'mint' parameter. Must be an integer timestamp in milliseconds."})
 		return
 	}
 	maxt, err := strconv.ParseInt(maxtStr, 10, 64)
 	if err != nil {
-		log.Printf("ERROR: Failed to parse 'maxt' parameter '%s': %v", maxtStr, err)
+		logger.Error("failed to parse 'maxt' parameter", "maxt", maxtStr, "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'maxt' parameter. Must be an integer timestamp in milliseconds."})
 		return
 	}
+	c.Set("mint", mint)
+	c.Set("maxt", maxt)
 
 	otherArgsStr := c.Query("args")
 	otherArgs := 0.0
 	if otherArgsStr != "" {
 		parsedArgs, err := strconv.ParseFloat(otherArgsStr, 64)
 		if err != nil {
-			log.Printf("[Error] Failed to parse args: %v", err)
+			logger.Error("failed to parse 'args' parameter", "args", otherArgsStr, "error", err)
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'args' parameter. Must be a float."})
 			return
 		}
 		otherArgs = parsedArgs
 	}
-	log.Printf("[Query] args=%.4f", otherArgs)
 
 	// Build label set
 	lsetBuilder := labels.NewBuilder(labels.Labels{})
@@ -179,33 +291,31 @@ func handleQuery(c *gin.Context) {
 			labelKey := k[len("label_"):]
 			labelValue := v[0]
 			lsetBuilder.Set(labelKey, labelValue)
-			log.Printf("[Label] %s=%s", labelKey, labelValue)
 		}
 	}
 	lsetBuilder.Set("fake_metric", metricName)
 	lset := lsetBuilder.Labels()
-	log.Printf("[LabelSet] Final lset: %v", lset)
 
 	curTime := time.Now().UnixMilli()
 	isCovered := ps.LookUp(lset, funcName, mint, maxt)
+	c.Set("covered", isCovered)
 	if !isCovered {
-		log.Printf("[Sketch] Data NOT covered for range [%d, %d] on %v for func=%s", mint, maxt, lset, funcName)
+		if sketchCoverageLog.Allow("not_covered:" + funcName) {
+			logger.Info("sketch data not covered for range", "lset", lset.String(), "func", funcName, "mint", mint, "maxt", maxt)
+		}
 		c.JSON(http.StatusAccepted, gin.H{
 			"status":  "pending",
 			"message": "Sketch data not yet available. Try again later.",
 		})
 		return
 	}
-	log.Printf("[Sketch] Data covered for range [%d, %d] on %v for func=%s", mint, maxt, lset, funcName)
 
 	vector, annotations := ps.Eval(funcName, lset, otherArgs, mint, maxt, curTime)
-	log.Printf("[Eval] Raw result length: %d", len(vector))
 
 	// Filter out NaN or invalid results
 	results := []map[string]interface{}{}
-	for i, sample := range vector {
+	for _, sample := range vector {
 		if math.IsNaN(sample.F) || sample.T == 0 {
-			log.Printf("[Eval] Skipping invalid sample #%d: timestamp=%d, value=%.4f", i, sample.T, sample.F)
 			continue
 		}
 		results = append(results, map[string]interface{}{
@@ -213,22 +323,17 @@ func handleQuery(c *gin.Context) {
 			"timestamp": sample.T,
 		})
 	}
+	c.Set("result_count", len(results))
 
 	// Prepare JSON response
 	response := gin.H{
 		"status": "success",
 		"data":   results,
 	}
-	if annotations != nil && len(annotations) > 0 {
+	if len(annotations) > 0 {
 		response["annotations"] = annotations
-		log.Printf("[Eval] Annotations: %+v", annotations)
-	}
-
-	if len(results) == 0 {
-		log.Printf("[Query] All samples are invalid or sketch not yet populated. Returning empty result.")
 	}
 
 	c.Header("Content-Type", "application/json")
 	c.JSON(http.StatusOK, response)
-	log.Printf("[Query] func=%s on lset=%v (range %d-%d) returned %d valid result(s).", funcName, lset, mint, maxt, len(results))
 }
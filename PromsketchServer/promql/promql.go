@@ -0,0 +1,163 @@
+// Package promql wraps the upstream PromQL parser so the Gin server can
+// accept real Prometheus query strings instead of the ad-hoc func/metric/args
+// parameters used by the legacy /query endpoint.
+//
+// Only a narrow slice of PromQL is understood: a single range-vector function
+// call (e.g. avg_over_time(metric{label="value"}[5m])) whose function is
+// sketch-eligible. Anything outside that shape is reported back to the
+// caller via ErrUnsupported so api.go can fall back to a scan-based
+// evaluator.
+package promql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zzylol/prometheus-sketches/model/labels"
+	"github.com/zzylol/prometheus-sketches/promql/parser"
+)
+
+// ErrUnsupported is returned by Parse when the expression is syntactically
+// valid PromQL but isn't something PromSketch can route to a sketch.
+type ErrUnsupported struct {
+	Expr string
+}
+
+func (e *ErrUnsupported) Error() string {
+	return fmt.Sprintf("promql: expression %q has no sketch-eligible evaluation path", e.Expr)
+}
+
+// sketchEligible lists the *_over_time functions PromSketch has a sketch
+// implementation for. Keep this in sync with the function names registered
+// in ps.Eval.
+var sketchEligible = map[string]bool{
+	"avg_over_time":                true,
+	"quantile_over_time":           true,
+	"entropy_over_time":            true,
+	"count_over_time":              true,
+	"sum_over_time":                true,
+	"min_over_time":                true,
+	"max_over_time":                true,
+	"stddev_over_time":             true,
+	"stdvar_over_time":             true,
+	"histogram_quantile_over_time": true,
+}
+
+// IsSketchEligible reports whether funcName has a sketch-backed
+// implementation in ps.Eval.
+func IsSketchEligible(funcName string) bool {
+	return sketchEligible[funcName]
+}
+
+// Query is the normalized form of a parsed range-vector function call,
+// ready to be handed to ps.LookUp / ps.Eval.
+type Query struct {
+	Func     string
+	Matchers []*labels.Matcher
+	Range    time.Duration
+	Arg      float64 // extra scalar argument, e.g. the phi in quantile_over_time
+}
+
+// Parse parses a PromQL query string and extracts a single sketch-eligible
+// range-vector call. If the expression doesn't match that shape, it returns
+// an *ErrUnsupported wrapping the original query so the caller can fall back
+// to a scan-based evaluator.
+func Parse(query string) (*Query, error) {
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return nil, fmt.Errorf("promql: parse error: %w", err)
+	}
+
+	call, ok := expr.(*parser.Call)
+	if !ok || len(call.Args) == 0 {
+		return nil, &ErrUnsupported{Expr: query}
+	}
+	if !IsSketchEligible(call.Func.Name) {
+		return nil, &ErrUnsupported{Expr: query}
+	}
+
+	matrixSelector, ok := call.Args[len(call.Args)-1].(*parser.MatrixSelector)
+	if !ok {
+		return nil, &ErrUnsupported{Expr: query}
+	}
+	vectorSelector, ok := matrixSelector.VectorSelector.(*parser.VectorSelector)
+	if !ok {
+		return nil, &ErrUnsupported{Expr: query}
+	}
+
+	q := &Query{
+		Func:     call.Func.Name,
+		Matchers: vectorSelector.LabelMatchers,
+		Range:    matrixSelector.Range,
+	}
+
+	// quantile_over_time(phi, metric[range]) carries its scalar argument as
+	// the first call arg.
+	if len(call.Args) == 2 {
+		numberLit, ok := call.Args[0].(*parser.NumberLiteral)
+		if !ok {
+			return nil, &ErrUnsupported{Expr: query}
+		}
+		q.Arg = numberLit.Val
+	}
+
+	return q, nil
+}
+
+// MetricName returns the value of the __name__ matcher, if present.
+func (q *Query) MetricName() string {
+	for _, m := range q.Matchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// MetricNameLabel is the label the JSON-API sketches are keyed on instead of
+// the PromQL-standard __name__: handleIngest, handleQuery, and the init()
+// fixture all write/read "fake_metric" (see main.go), not labels.MetricName.
+// ToLabels (and any other matcher-based lookup against the sketch registry,
+// e.g. api.go's scanEval) must translate PromQL's __name__ matcher to it so
+// queries resolve against those sketches. Series ingested through
+// /api/v1/write keep their __name__ label as sent, so lookups translated
+// this way do not match them.
+const MetricNameLabel = "fake_metric"
+
+// TranslateMetricName rewrites any __name__ matcher in matchers to match
+// against MetricNameLabel instead, leaving every other matcher untouched.
+func TranslateMetricName(matchers []*labels.Matcher) []*labels.Matcher {
+	out := make([]*labels.Matcher, len(matchers))
+	for i, m := range matchers {
+		if m.Name == labels.MetricName {
+			translated, err := labels.NewMatcher(m.Type, MetricNameLabel, m.Value)
+			if err == nil {
+				out[i] = translated
+				continue
+			}
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// ToLabels builds a concrete labels.Labels from the query's equality
+// matchers, which is what ps.LookUp/ps.Eval expect. Non-equality matchers
+// (=~, !=, !~) aren't resolvable against a single series and are ignored
+// here; callers that need matcher-based series selection should use
+// /api/v1/series instead. The __name__ matcher is translated to
+// MetricNameLabel, since that's the label sketches are actually keyed on.
+func (q *Query) ToLabels() labels.Labels {
+	b := labels.NewBuilder(labels.Labels{})
+	for _, m := range q.Matchers {
+		if m.Type != labels.MatchEqual {
+			continue
+		}
+		name := m.Name
+		if name == labels.MetricName {
+			name = MetricNameLabel
+		}
+		b.Set(name, m.Value)
+	}
+	return b.Labels()
+}
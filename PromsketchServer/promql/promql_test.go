@@ -0,0 +1,93 @@
+package promql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zzylol/prometheus-sketches/model/labels"
+)
+
+func TestParseQuantileOverTime(t *testing.T) {
+	q, err := Parse(`quantile_over_time(0.9, m{l="v"}[5m])`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if q.Func != "quantile_over_time" {
+		t.Errorf("Func = %q, want quantile_over_time", q.Func)
+	}
+	if q.Arg != 0.9 {
+		t.Errorf("Arg = %v, want 0.9", q.Arg)
+	}
+	if q.Range != 5*time.Minute {
+		t.Errorf("Range = %v, want 5m", q.Range)
+	}
+	if got := q.MetricName(); got != "m" {
+		t.Errorf("MetricName() = %q, want %q", got, "m")
+	}
+}
+
+func TestParseAvgOverTime(t *testing.T) {
+	q, err := Parse(`avg_over_time(metric{machineid="machine_0"}[1m])`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if q.Func != "avg_over_time" {
+		t.Errorf("Func = %q, want avg_over_time", q.Func)
+	}
+	if q.Arg != 0 {
+		t.Errorf("Arg = %v, want 0 (no scalar arg for avg_over_time)", q.Arg)
+	}
+}
+
+func TestParseUnsupportedExpression(t *testing.T) {
+	_, err := Parse(`up{job="x"} + 1`)
+	if _, ok := err.(*ErrUnsupported); !ok {
+		t.Fatalf("Parse of unsupported expr returned %T, want *ErrUnsupported", err)
+	}
+}
+
+func TestParseRejectsNonSketchFunction(t *testing.T) {
+	_, err := Parse(`rate(metric{l="v"}[5m])`)
+	if _, ok := err.(*ErrUnsupported); !ok {
+		t.Fatalf("Parse of non-sketch-eligible func returned %T, want *ErrUnsupported", err)
+	}
+}
+
+func TestToLabelsTranslatesMetricName(t *testing.T) {
+	q, err := Parse(`avg_over_time(fake_machine_metric{machineid="machine_0"}[1m])`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	lset := q.ToLabels()
+
+	if got := lset.Get(MetricNameLabel); got != "fake_machine_metric" {
+		t.Errorf("lset.Get(%q) = %q, want %q", MetricNameLabel, got, "fake_machine_metric")
+	}
+	if got := lset.Get("__name__"); got != "" {
+		t.Errorf("lset.Get(__name__) = %q, want empty: __name__ should be translated away", got)
+	}
+	if got := lset.Get("machineid"); got != "machine_0" {
+		t.Errorf("machineid = %q, want machine_0", got)
+	}
+}
+
+func TestTranslateMetricName(t *testing.T) {
+	nameMatcher, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "fake_machine_metric")
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	otherMatcher, err := labels.NewMatcher(labels.MatchEqual, "machineid", "machine_0")
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+
+	translated := TranslateMetricName([]*labels.Matcher{nameMatcher, otherMatcher})
+
+	if translated[0].Name != MetricNameLabel {
+		t.Errorf("translated[0].Name = %q, want %q", translated[0].Name, MetricNameLabel)
+	}
+	if translated[1].Name != "machineid" {
+		t.Errorf("translated[1].Name = %q, want machineid (untouched)", translated[1].Name)
+	}
+}
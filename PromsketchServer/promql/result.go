@@ -0,0 +1,79 @@
+package promql
+
+import "strconv"
+
+// APIResponse mirrors Prometheus's `/api/v1/query*` envelope so existing
+// Prometheus HTTP API clients (Grafana included) can be pointed at
+// PromSketch without a custom result parser.
+type APIResponse struct {
+	Status    string     `json:"status"`
+	Data      *QueryData `json:"data,omitempty"`
+	ErrorType string     `json:"errorType,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Warnings  []string   `json:"warnings,omitempty"`
+}
+
+// QueryData is the `data` field of APIResponse.
+type QueryData struct {
+	ResultType string      `json:"resultType"`
+	Result     interface{} `json:"result"`
+}
+
+// Sample is one series in Prometheus's wire format: either a single "value"
+// (instant query) or a "values" list (range query), each [timestamp, value]
+// pair with the value encoded as a string per the API spec.
+type Sample struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values,omitempty"`
+	Value  *[2]interface{}   `json:"value,omitempty"`
+}
+
+// Point is the minimal (timestamp, value) pair ps.Eval's result vector is
+// converted into before formatting, so this package doesn't need to import
+// the sketch engine's own sample type.
+type Point struct {
+	T int64 // milliseconds
+	F float64
+}
+
+// NewVectorResponse builds a "status":"success", resultType "vector"
+// response for an instant query (one sample per series).
+func NewVectorResponse(metric map[string]string, p Point) *APIResponse {
+	return &APIResponse{
+		Status: "success",
+		Data: &QueryData{
+			ResultType: "vector",
+			Result:     []Sample{{Metric: metric, Value: &[2]interface{}{float64(p.T) / 1000, formatValue(p.F)}}},
+		},
+	}
+}
+
+// NewMatrixResponse builds a "status":"success", resultType "matrix"
+// response for a range query (many samples for one series).
+func NewMatrixResponse(metric map[string]string, points []Point) *APIResponse {
+	values := make([][2]interface{}, 0, len(points))
+	for _, p := range points {
+		values = append(values, [2]interface{}{float64(p.T) / 1000, formatValue(p.F)})
+	}
+	return &APIResponse{
+		Status: "success",
+		Data: &QueryData{
+			ResultType: "matrix",
+			Result:     []Sample{{Metric: metric, Values: values}},
+		},
+	}
+}
+
+// NewErrorResponse builds a "status":"error" response matching the shape
+// Prometheus returns for bad_data / internal errors.
+func NewErrorResponse(errType, msg string) *APIResponse {
+	return &APIResponse{
+		Status:    "error",
+		ErrorType: errType,
+		Error:     msg,
+	}
+}
+
+func formatValue(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
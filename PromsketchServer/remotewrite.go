@@ -0,0 +1,222 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/snappy"
+	"github.com/zzylol/prometheus-sketches/model/histogram"
+	"github.com/zzylol/prometheus-sketches/model/labels"
+	"github.com/zzylol/prometheus-sketches/prompb"
+	writev2 "github.com/zzylol/prometheus-sketches/prompb/io/prometheus/write/v2"
+)
+
+// remoteWriteVersion identifies which wire format a POST to /api/v1/write
+// is using, selected by the request's Content-Type per the Remote Write 2.0
+// negotiation rules.
+type remoteWriteVersion int
+
+const (
+	remoteWriteV1 remoteWriteVersion = iota
+	remoteWriteV2
+)
+
+// registerRemoteWriteRoute wires up the Prometheus remote_write sink so
+// PromSketch can be scraped directly via a `remote_write` block instead of
+// the bespoke JSON /ingest endpoint.
+func registerRemoteWriteRoute(router *gin.Engine) {
+	router.POST("/api/v1/write", handleRemoteWrite)
+}
+
+// handleRemoteWrite accepts a snappy-compressed Prometheus Remote Write
+// payload (v1 prompb.WriteRequest or v2 io.prometheus.write.v2.Request) and
+// feeds every sample into ps.SketchInsert.
+func handleRemoteWrite(c *gin.Context) {
+	compressed, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body: " + err.Error()})
+		return
+	}
+	raw, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to decode snappy payload: " + err.Error()})
+		return
+	}
+
+	switch detectRemoteWriteVersion(c) {
+	case remoteWriteV2:
+		handleRemoteWriteV2(c, raw)
+	default:
+		handleRemoteWriteV1(c, raw)
+	}
+}
+
+// detectRemoteWriteVersion follows the Remote Write 2.0 spec: the version is
+// carried in the Content-Type media type parameter, e.g.
+// "application/x-protobuf;proto=io.prometheus.write.v2.Request".
+func detectRemoteWriteVersion(c *gin.Context) remoteWriteVersion {
+	if contentType := c.GetHeader("Content-Type"); contentType != "" {
+		if strings.Contains(contentType, "io.prometheus.write.v2.Request") {
+			return remoteWriteV2
+		}
+	}
+	return remoteWriteV1
+}
+
+func handleRemoteWriteV1(c *gin.Context, raw []byte) {
+	var req prompb.WriteRequest
+	if err := req.Unmarshal(raw); err != nil {
+		log.Printf("[remote-write v1] failed to unmarshal WriteRequest: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid WriteRequest: " + err.Error()})
+		return
+	}
+
+	var samplesWritten, histogramsWritten, exemplarsWritten int
+
+	for _, ts := range req.Timeseries {
+		lsetBuilder := labels.NewBuilder(labels.Labels{})
+		for _, l := range ts.Labels {
+			lsetBuilder.Set(l.Name, l.Value)
+		}
+		lset := lsetBuilder.Labels()
+		if !ensureSketches(lset) {
+			continue
+		}
+
+		for _, s := range ts.Samples {
+			if err := ps.SketchInsert(lset, s.Timestamp, s.Value); err != nil {
+				log.Printf("[remote-write v1] insert failed for %v: %v", lset, err)
+				continue
+			}
+			samplesWritten++
+		}
+		exemplarsWritten += len(ts.Exemplars)
+
+		for _, h := range ts.Histograms {
+			if err := ps.EnsureSketch(lset, "histogram_quantile_over_time", defaultSketchConfig); err != nil {
+				log.Printf("[remote-write v1] EnsureSketch(%v, histogram_quantile_over_time) failed: %v", lset, err)
+			}
+			if err := ps.SketchInsertHistogram(lset, h.Timestamp, histogramFromPrompb(h)); err != nil {
+				log.Printf("[remote-write v1] histogram insert failed for %v: %v", lset, err)
+				continue
+			}
+			histogramsWritten++
+		}
+	}
+
+	setRemoteWriteHeaders(c, samplesWritten, histogramsWritten, exemplarsWritten)
+	c.Status(http.StatusNoContent)
+}
+
+// handleRemoteWriteV2 decodes the 2.x wire format, where every timeseries's
+// label names/values and unit/help strings are offsets into a single
+// request-wide symbol table instead of being repeated inline.
+func handleRemoteWriteV2(c *gin.Context, raw []byte) {
+	var req writev2.Request
+	if err := req.Unmarshal(raw); err != nil {
+		log.Printf("[remote-write v2] failed to unmarshal Request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid v2 Request: " + err.Error()})
+		return
+	}
+
+	var samplesWritten, histogramsWritten, exemplarsWritten int
+
+	for _, ts := range req.Timeseries {
+		lset := labelsFromSymbolized(req.Symbols, ts.LabelsRefs)
+		if !ensureSketches(lset) {
+			continue
+		}
+
+		for _, s := range ts.Samples {
+			if err := ps.SketchInsert(lset, s.Timestamp, s.Value); err != nil {
+				log.Printf("[remote-write v2] insert failed for %v: %v", lset, err)
+				continue
+			}
+			samplesWritten++
+		}
+		exemplarsWritten += len(ts.Exemplars)
+
+		for _, h := range ts.Histograms {
+			if err := ps.EnsureSketch(lset, "histogram_quantile_over_time", defaultSketchConfig); err != nil {
+				log.Printf("[remote-write v2] EnsureSketch(%v, histogram_quantile_over_time) failed: %v", lset, err)
+			}
+			if err := ps.SketchInsertHistogram(lset, h.Timestamp, histogramFromWritev2(h)); err != nil {
+				log.Printf("[remote-write v2] histogram insert failed for %v: %v", lset, err)
+				continue
+			}
+			histogramsWritten++
+		}
+	}
+
+	setRemoteWriteHeaders(c, samplesWritten, histogramsWritten, exemplarsWritten)
+	c.Status(http.StatusNoContent)
+}
+
+// labelsFromSymbolized rebuilds a labels.Labels from a v2 LabelsRefs slice,
+// which alternates name-offset, value-offset pairs into the request's
+// shared symbols table.
+func labelsFromSymbolized(symbols []string, refs []uint32) labels.Labels {
+	b := labels.NewBuilder(labels.Labels{})
+	for i := 0; i+1 < len(refs); i += 2 {
+		name := symbols[refs[i]]
+		value := symbols[refs[i+1]]
+		b.Set(name, value)
+	}
+	return b.Labels()
+}
+
+// histogramFromWritev2 converts a v2 wire histogram (schema + zero
+// bucket + exponential positive/negative spans-and-deltas) into the sparse
+// histogram type ps.SketchInsertHistogram expects.
+func histogramFromWritev2(h writev2.Histogram) *histogram.Histogram {
+	p := &HistogramPayload{
+		Schema:         h.Schema,
+		ZeroThreshold:  h.ZeroThreshold,
+		ZeroCount:      h.GetZeroCountInt(),
+		Count:          h.GetCountInt(),
+		Sum:            h.Sum,
+		PositiveDeltas: h.PositiveDeltas,
+		NegativeDeltas: h.NegativeDeltas,
+	}
+	for _, s := range h.PositiveSpans {
+		p.PositiveSpans = append(p.PositiveSpans, HistogramSpan{Offset: s.Offset, Length: s.Length})
+	}
+	for _, s := range h.NegativeSpans {
+		p.NegativeSpans = append(p.NegativeSpans, HistogramSpan{Offset: s.Offset, Length: s.Length})
+	}
+	return p.toHistogram()
+}
+
+// histogramFromPrompb converts a v1 wire histogram into the sparse
+// histogram type ps.SketchInsertHistogram expects. v1's prompb.Histogram
+// carries the same schema/zero-bucket/spans-and-deltas shape as v2's, just
+// without the symbol-table indirection, so this mirrors
+// histogramFromWritev2 field-for-field.
+func histogramFromPrompb(h prompb.Histogram) *histogram.Histogram {
+	p := &HistogramPayload{
+		Schema:         h.Schema,
+		ZeroThreshold:  h.ZeroThreshold,
+		ZeroCount:      h.GetZeroCountInt(),
+		Count:          h.GetCountInt(),
+		Sum:            h.Sum,
+		PositiveDeltas: h.PositiveDeltas,
+		NegativeDeltas: h.NegativeDeltas,
+	}
+	for _, s := range h.PositiveSpans {
+		p.PositiveSpans = append(p.PositiveSpans, HistogramSpan{Offset: s.Offset, Length: s.Length})
+	}
+	for _, s := range h.NegativeSpans {
+		p.NegativeSpans = append(p.NegativeSpans, HistogramSpan{Offset: s.Offset, Length: s.Length})
+	}
+	return p.toHistogram()
+}
+
+func setRemoteWriteHeaders(c *gin.Context, samples, histograms, exemplars int) {
+	c.Header("X-Prometheus-Remote-Write-Samples-Written", strconv.Itoa(samples))
+	c.Header("X-Prometheus-Remote-Write-Histograms-Written", strconv.Itoa(histograms))
+	c.Header("X-Prometheus-Remote-Write-Exemplars-Written", strconv.Itoa(exemplars))
+}
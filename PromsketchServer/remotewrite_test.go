@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/zzylol/prometheus-sketches/prompb"
+	writev2 "github.com/zzylol/prometheus-sketches/prompb/io/prometheus/write/v2"
+)
+
+func TestLabelsFromSymbolized(t *testing.T) {
+	symbols := []string{"", "__name__", "requests_total", "machineid", "machine_0"}
+	refs := []uint32{1, 2, 3, 4}
+
+	lset := labelsFromSymbolized(symbols, refs)
+
+	if got := lset.Get("__name__"); got != "requests_total" {
+		t.Errorf("__name__ = %q, want %q", got, "requests_total")
+	}
+	if got := lset.Get("machineid"); got != "machine_0" {
+		t.Errorf("machineid = %q, want %q", got, "machine_0")
+	}
+}
+
+func TestHistogramFromWritev2MatchesPrompb(t *testing.T) {
+	v1h := prompb.Histogram{
+		Schema:         1,
+		ZeroThreshold:  0.001,
+		Sum:            12.5,
+		PositiveSpans:  []prompb.BucketSpan{{Offset: 0, Length: 2}},
+		PositiveDeltas: []int64{1, 1},
+	}
+	v2h := writev2.Histogram{
+		Schema:         1,
+		ZeroThreshold:  0.001,
+		Sum:            12.5,
+		PositiveSpans:  []writev2.BucketSpan{{Offset: 0, Length: 2}},
+		PositiveDeltas: []int64{1, 1},
+	}
+
+	got1 := histogramFromPrompb(v1h)
+	got2 := histogramFromWritev2(v2h)
+
+	if got1.Schema != got2.Schema || got1.Sum != got2.Sum {
+		t.Fatalf("histogramFromPrompb/histogramFromWritev2 diverged for equivalent input: %+v vs %+v", got1, got2)
+	}
+	if len(got1.PositiveSpans) != len(got2.PositiveSpans) {
+		t.Fatalf("PositiveSpans length mismatch: %v vs %v", got1.PositiveSpans, got2.PositiveSpans)
+	}
+}
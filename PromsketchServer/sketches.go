@@ -0,0 +1,223 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Froot-NetSys/PromSketch-Standalone/PromsketchServer/admission"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/zzylol/prometheus-sketches/model/labels"
+	"github.com/zzylol/prometheus-sketches/promql/parser"
+)
+
+// parseMatchSelector parses a PromQL label-selector string (e.g.
+// `{machineid="machine_0"}`) into matchers usable against the series
+// registry.
+func parseMatchSelector(sel string) ([]*labels.Matcher, error) {
+	if sel == "" {
+		return nil, nil
+	}
+	return parser.ParseMetricSelector(sel)
+}
+
+// defaultSketchFuncs are the query functions every dynamically admitted
+// series gets a sketch for, mirroring what init() pre-allocates for the
+// machine_0..N fixture series.
+var defaultSketchFuncs = []string{"avg_over_time", "quantile_over_time", "entropy_over_time"}
+
+var (
+	seriesActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "promsketch_series_active",
+		Help: "Number of distinct label sets currently backed by a live sketch.",
+	})
+	seriesEvictedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "promsketch_series_evicted_total",
+		Help: "Total number of series whose sketches were torn down by admission-policy eviction (series cap or TTL) or DELETE /admin/sketches.",
+	})
+)
+
+// admissionPolicy gates dynamic sketch creation for label sets PromSketch
+// hasn't seen before (primarily from remote-write, which has no fixed
+// series list like the init() fixture data does). It defaults to an
+// unbounded, no-TTL policy so existing deployments that don't ship a config
+// file keep working exactly as before.
+var admissionPolicy admission.Policy
+
+func init() {
+	cfg := admission.Config{}
+	if path := os.Getenv("PROMSKETCH_ADMISSION_CONFIG"); path != "" {
+		loaded, err := admission.LoadConfig(path)
+		if err != nil {
+			log.Printf("Error loading admission policy config %q, falling back to unbounded admission: %v", path, err)
+		} else {
+			cfg = loaded
+		}
+	}
+	policy, err := admission.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to build admission policy: %v", err)
+	}
+	admissionPolicy = policy
+
+	go runEvictionLoop()
+}
+
+// ensureSketches is the lazy-registration fast path: given a label set that
+// handleIngest or a remote-write handler just saw a sample for, make sure
+// every default query function has a sketch before the caller inserts into
+// it. Unlike init()'s eager allocation, this only pays the allocation cost
+// for label sets that actually show up.
+func ensureSketches(lset labels.Labels) bool {
+	ok, evicted := admissionPolicy.Admit(lset)
+	if !ok {
+		return false
+	}
+	evictSketches(evicted)
+	for _, fn := range defaultSketchFuncs {
+		if err := ps.EnsureSketch(lset, fn, defaultSketchConfig); err != nil {
+			log.Printf("EnsureSketch(%v, %s) failed: %v", lset, fn, err)
+		}
+	}
+	admissionPolicy.Touch(lset)
+	rememberSeries(lset)
+	seriesActive.Set(float64(len(knownSeries())))
+	return true
+}
+
+// defaultSketchConfig mirrors the window/cardinality/value-scale constants
+// init() uses for the fixture series, so dynamically admitted series get
+// the same sketch shape.
+var defaultSketchConfig = promsketchSketchConfig{
+	TimeWindow: 60 * 1000,
+	ItemWindow: 100000,
+	ValueScale: 10000,
+}
+
+// promsketchSketchConfig is the cfg argument ps.EnsureSketch expects; it
+// mirrors the positional args NewSketchCacheInstance already takes.
+type promsketchSketchConfig struct {
+	TimeWindow int64
+	ItemWindow int64
+	ValueScale float64
+}
+
+// runEvictionLoop periodically asks the admission policy which series have
+// gone idle past their TTL, or still push the series count over its cap,
+// and tears their sketches down.
+func runEvictionLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		evictSketches(admissionPolicy.Evictable())
+		seriesActive.Set(float64(len(knownSeries())))
+	}
+}
+
+// evictSketches tears down the sketches for every label set the admission
+// policy has decided to evict, whether that decision came from the cap
+// being hit synchronously in Admit, the periodic TTL/cap sweep, or an
+// explicit admin delete request. It returns how many were actually torn
+// down, since ps.DeleteSketch can fail for an individual label set.
+func evictSketches(evicted []labels.Labels) int {
+	var deleted int
+	for _, lset := range evicted {
+		if err := ps.DeleteSketch(lset); err != nil {
+			log.Printf("DeleteSketch(%v) during eviction failed: %v", lset, err)
+			continue
+		}
+		admissionPolicy.Forget(lset)
+		forgetSeries(lset)
+		seriesEvictedTotal.Inc()
+		deleted++
+	}
+	return deleted
+}
+
+// registerAdminRoutes wires up operator endpoints for managing sketches
+// outside of the normal ingest-driven lifecycle, plus the Prometheus
+// /metrics collector.
+func registerAdminRoutes(router *gin.Engine) {
+	router.POST("/admin/sketches", handleAdminCreateSketch)
+	router.DELETE("/admin/sketches", handleAdminDeleteSketches)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+}
+
+// adminCreateSketchRequest is the POST /admin/sketches body: an explicit
+// label set plus which functions to allocate sketches for.
+type adminCreateSketchRequest struct {
+	Labels map[string]string `json:"labels"`
+	Funcs  []string          `json:"funcs"`
+}
+
+func handleAdminCreateSketch(c *gin.Context) {
+	var req adminCreateSketchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	if len(req.Funcs) == 0 {
+		req.Funcs = defaultSketchFuncs
+	}
+
+	b := labels.NewBuilder(labels.Labels{})
+	for k, v := range req.Labels {
+		b.Set(k, v)
+	}
+	lset := b.Labels()
+
+	ok, evicted := admissionPolicy.Admit(lset)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "label set rejected by admission policy"})
+		return
+	}
+	evictSketches(evicted)
+	for _, fn := range req.Funcs {
+		if err := ps.EnsureSketch(lset, fn, defaultSketchConfig); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	admissionPolicy.Touch(lset)
+	rememberSeries(lset)
+	seriesActive.Set(float64(len(knownSeries())))
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// handleAdminDeleteSketches implements DELETE /admin/sketches?match={...},
+// evicting every known series matching the given label selector.
+func handleAdminDeleteSketches(c *gin.Context) {
+	sel := c.Query("match")
+	if sel == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'match' is required: refusing to delete every known series"})
+		return
+	}
+	matchers, err := parseMatchSelector(sel)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'match' selector: " + err.Error()})
+		return
+	}
+
+	var toDelete []labels.Labels
+	for _, lset := range knownLabelSets() {
+		if matchesAll(lset, matchers) {
+			toDelete = append(toDelete, lset)
+		}
+	}
+	deleted := evictSketches(toDelete)
+	seriesActive.Set(float64(len(knownSeries())))
+	c.JSON(http.StatusOK, gin.H{"status": "success", "deleted": deleted})
+}
+
+func matchesAll(lset labels.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(lset.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// analyzeResult is the stats `analyze` reports for a single series, modeled
+// after `promtool query analyze`'s value-distribution summary. PromSketch's
+// server API only ever returns already sketch-approximated values (there is
+// no exported way to introspect a live sketch's internal buckets or to
+// serialize one to disk), so unlike promtool this can't compare an estimate
+// against ground truth - it summarizes the values the server actually
+// returned.
+type analyzeResult struct {
+	Metric      string  `json:"metric"`
+	SampleCount int     `json:"sample_count"`
+	Min         float64 `json:"min"`
+	Max         float64 `json:"max"`
+	Mean        float64 `json:"mean"`
+	StdDev      float64 `json:"stddev"`
+	P50         float64 `json:"p50"`
+	P99         float64 `json:"p99"`
+}
+
+// runAnalyze implements `promsketchctl analyze`: samples a series from a
+// live server over [--start,--end] and reports a distribution summary of
+// the values it returned.
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:7000", "PromSketch server address")
+	expr := fs.String("expr", "", "PromQL expression identifying the series to analyze")
+	start := fs.String("start", "", "window start (RFC3339)")
+	end := fs.String("end", "", "window end (RFC3339)")
+	step := fs.String("step", "1s", "query resolution step")
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *expr == "" || *start == "" || *end == "" {
+		return fmt.Errorf("--expr, --start and --end are required")
+	}
+
+	result, err := analyzeLive(*server, *expr, *start, *end, *step)
+	if err != nil {
+		return err
+	}
+
+	return printAnalyzeResult(result, *format)
+}
+
+// analyzeLive samples the series over the window via the server's
+// /api/v1/query_range endpoint and summarizes the values it returned.
+func analyzeLive(server, expr, start, end, step string) (analyzeResult, error) {
+	q := url.Values{
+		"query": {expr},
+		"start": {start},
+		"end":   {end},
+		"step":  {step},
+	}
+	body, err := httpGet(server+"/api/v1/query_range", q)
+	if err != nil {
+		return analyzeResult{}, err
+	}
+
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][2]interface{}  `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return analyzeResult{}, fmt.Errorf("parsing server response: %w", err)
+	}
+	if resp.Status != "success" {
+		return analyzeResult{}, fmt.Errorf("query failed: %s", resp.Error)
+	}
+	if len(resp.Data.Result) == 0 {
+		return analyzeResult{}, fmt.Errorf("no series matched %q in [%s, %s]", expr, start, end)
+	}
+
+	series := resp.Data.Result[0]
+	values := make([]float64, 0, len(series.Values))
+	for _, v := range series.Values {
+		var f float64
+		if _, err := fmt.Sscanf(fmt.Sprint(v[1]), "%f", &f); err == nil {
+			values = append(values, f)
+		}
+	}
+
+	return summarize(formatMetric(series.Metric), values), nil
+}
+
+// summarize computes the distribution stats analyzeResult reports from a
+// series of sampled values.
+func summarize(metric string, values []float64) analyzeResult {
+	result := analyzeResult{Metric: metric, SampleCount: len(values)}
+	if len(values) == 0 {
+		return result
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	result.Min = sorted[0]
+	result.Max = sorted[len(sorted)-1]
+	result.P50 = quantile(sorted, 0.50)
+	result.P99 = quantile(sorted, 0.99)
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	result.Mean = sum / float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		d := v - result.Mean
+		variance += d * d
+	}
+	result.StdDev = math.Sqrt(variance / float64(len(values)))
+
+	return result
+}
+
+// quantile assumes sorted is already sorted ascending.
+func quantile(sorted []float64, q float64) float64 {
+	idx := int(q * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+func printAnalyzeResult(r analyzeResult, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	w := newTableWriter(os.Stdout)
+	w.Header("METRIC", "SAMPLES", "MIN", "MAX", "MEAN", "STDDEV", "P50", "P99")
+	w.Row(
+		r.Metric,
+		fmt.Sprint(r.SampleCount),
+		fmt.Sprintf("%.4f", r.Min),
+		fmt.Sprintf("%.4f", r.Max),
+		fmt.Sprintf("%.4f", r.Mean),
+		fmt.Sprintf("%.4f", r.StdDev),
+		fmt.Sprintf("%.4f", r.P50),
+		fmt.Sprintf("%.4f", r.P99),
+	)
+	return w.Flush()
+}
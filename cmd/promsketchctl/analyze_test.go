@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestSummarize(t *testing.T) {
+	r := summarize("test_metric", []float64{1, 2, 3, 4, 5})
+
+	if r.SampleCount != 5 {
+		t.Errorf("SampleCount = %d, want 5", r.SampleCount)
+	}
+	if r.Min != 1 || r.Max != 5 {
+		t.Errorf("Min/Max = %v/%v, want 1/5", r.Min, r.Max)
+	}
+	if r.Mean != 3 {
+		t.Errorf("Mean = %v, want 3", r.Mean)
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	r := summarize("test_metric", nil)
+	if r.SampleCount != 0 {
+		t.Errorf("SampleCount = %d, want 0", r.SampleCount)
+	}
+}
@@ -0,0 +1,52 @@
+// Command promsketchctl is a promtool-style CLI for querying a running
+// PromSketch server and summarizing the value distribution of a series over
+// a window, without having to script curl/jq against the HTTP API by hand.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "query":
+		err = runQuery(os.Args[2:])
+	case "analyze":
+		err = runAnalyze(os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "promsketchctl: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "promsketchctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `promsketchctl is a command-line tool for querying and analyzing a PromSketch server.
+
+Usage:
+
+  promsketchctl query instant  --server <addr> --expr <promql> [--time <rfc3339>] [--format table|json]
+  promsketchctl query range    --server <addr> --expr <promql> --start <rfc3339> --end <rfc3339> --step <dur> [--format table|json]
+  promsketchctl analyze        --server <addr> --expr <promql> --start <rfc3339> --end <rfc3339> [--step <dur>] [--format table|json]
+
+"analyze" samples a series over [--start,--end] and reports a distribution
+summary of the values the server returned (min/max/mean/stddev/p50/p99).
+PromSketch's server API only ever returns already sketch-approximated
+values, so this describes what came back rather than an estimate's error
+against ground truth.`)
+}
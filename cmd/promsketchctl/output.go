@@ -0,0 +1,29 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// tableWriter is a thin wrapper around text/tabwriter for the column-aligned
+// "table" output format shared by every subcommand.
+type tableWriter struct {
+	tw *tabwriter.Writer
+}
+
+func newTableWriter(w io.Writer) *tableWriter {
+	return &tableWriter{tw: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)}
+}
+
+func (t *tableWriter) Header(cols ...string) {
+	t.Row(cols...)
+}
+
+func (t *tableWriter) Row(cols ...string) {
+	_, _ = io.WriteString(t.tw, strings.Join(cols, "\t")+"\n")
+}
+
+func (t *tableWriter) Flush() error {
+	return t.tw.Flush()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// runQuery implements `promsketchctl query instant|range`.
+func runQuery(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("query requires a subcommand: instant or range")
+	}
+	switch args[0] {
+	case "instant":
+		return runQueryInstant(args[1:])
+	case "range":
+		return runQueryRange(args[1:])
+	default:
+		return fmt.Errorf("unknown query subcommand %q (want instant or range)", args[0])
+	}
+}
+
+func runQueryInstant(args []string) error {
+	fs := flag.NewFlagSet("query instant", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:7000", "PromSketch server address")
+	expr := fs.String("expr", "", "PromQL expression to evaluate")
+	timeStr := fs.String("time", "", "evaluation time (RFC3339); defaults to now")
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *expr == "" {
+		return fmt.Errorf("--expr is required")
+	}
+
+	q := url.Values{"query": {*expr}}
+	if *timeStr != "" {
+		q.Set("time", *timeStr)
+	}
+	body, err := httpGet(*server+"/api/v1/query", q)
+	if err != nil {
+		return err
+	}
+	return printAPIResponse(body, *format)
+}
+
+func runQueryRange(args []string) error {
+	fs := flag.NewFlagSet("query range", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:7000", "PromSketch server address")
+	expr := fs.String("expr", "", "PromQL expression to evaluate")
+	start := fs.String("start", "", "range start (RFC3339)")
+	end := fs.String("end", "", "range end (RFC3339)")
+	step := fs.Duration("step", time.Minute, "query resolution step")
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *expr == "" || *start == "" || *end == "" {
+		return fmt.Errorf("--expr, --start and --end are required")
+	}
+
+	q := url.Values{
+		"query": {*expr},
+		"start": {*start},
+		"end":   {*end},
+		"step":  {step.String()},
+	}
+	body, err := httpGet(*server+"/api/v1/query_range", q)
+	if err != nil {
+		return err
+	}
+	return printAPIResponse(body, *format)
+}
+
+func httpGet(base string, q url.Values) ([]byte, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --server address: %w", err)
+	}
+	u.RawQuery = q.Encode()
+
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("server returned %s: %s", resp.Status, body)
+	}
+	return body, nil
+}
+
+func printAPIResponse(body []byte, format string) error {
+	if format == "json" {
+		var pretty map[string]interface{}
+		if err := json.Unmarshal(body, &pretty); err != nil {
+			// Not valid JSON to re-indent; just print what we got.
+			_, err := os.Stdout.Write(body)
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(pretty)
+	}
+
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Value  [2]interface{}    `json:"value"`
+				Values [][2]interface{}  `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("parsing server response: %w", err)
+	}
+	if resp.Status != "success" {
+		return fmt.Errorf("query failed: %s", resp.Error)
+	}
+
+	w := newTableWriter(os.Stdout)
+	w.Header("METRIC", "TIMESTAMP", "VALUE")
+	for _, series := range resp.Data.Result {
+		rows := series.Values
+		if series.Value != [2]interface{}{} {
+			rows = [][2]interface{}{series.Value}
+		}
+		for _, row := range rows {
+			w.Row(formatMetric(series.Metric), fmt.Sprint(row[0]), fmt.Sprint(row[1]))
+		}
+	}
+	return w.Flush()
+}
+
+func formatMetric(metric map[string]string) string {
+	name := metric["__name__"]
+	var labelPairs string
+	first := true
+	for k, v := range metric {
+		if k == "__name__" {
+			continue
+		}
+		if !first {
+			labelPairs += ","
+		}
+		labelPairs += fmt.Sprintf("%s=%q", k, v)
+		first = false
+	}
+	return fmt.Sprintf("%s{%s}", name, labelPairs)
+}